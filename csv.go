@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// NewCSVParser returns a Filterer that parses CSV (or, with delimiter set
+// to '\t', TSV) input using encoding/csv, so quoted fields containing the
+// delimiter, embedded newlines, and escaped quotes parse correctly.
+//
+// keyCol selects which column of each row becomes the map key. The
+// remaining columns are re-encoded as a CSV record (using the same
+// delimiter) and stored as the value, so they can be written back out as
+// CSV. The key column is not part of the stored value, and encoding/csv
+// normalizes quoting, so the stored text can differ from the input row.
+func NewCSVParser(ctx context.Context, m *InputMap, delimiter rune, keyCol int) FilterFunc {
+	return func(in io.Reader, out, _ io.Writer) error {
+		m.Format.Kind = "csv"
+		m.Format.Delimiter = delimiter
+
+		r := csv.NewReader(in)
+		r.Comma = delimiter
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+				// DO NOTHING
+			}
+
+			record, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("csv: %w", err)
+			}
+
+			if keyCol < 0 || keyCol >= len(record) {
+				return fmt.Errorf("csv: key column %d out of range (row has %d columns)", keyCol, len(record))
+			}
+			key := record[keyCol]
+			if strings.ContainsAny(key, "\n\r") {
+				return fmt.Errorf("csv: key column %d contains a newline", keyCol)
+			}
+
+			value := append(append([]string{}, record[:keyCol]...), record[keyCol+1:]...)
+			encoded, err := encodeCSVRecord(value, delimiter)
+			if err != nil {
+				return fmt.Errorf("csv: %w", err)
+			}
+
+			if m.Format.Columns == nil {
+				m.Format.Columns = make([]string, len(value))
+				for i := range value {
+					m.Format.Columns[i] = strconv.Itoa(i + 1)
+				}
+			}
+
+			Append(&m.Map, key, encoded)
+			if _, err := out.Write(append([]byte(key), LF)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// encodeCSVRecord re-encodes fields as a single CSV record using delimiter,
+// trimming the writer's trailing record terminator.
+func encodeCSVRecord(fields []string, delimiter rune) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = delimiter
+	if err := w.Write(fields); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestNewCSVParser(t *testing.T) {
+	tests := []struct {
+		name      string
+		delimiter rune
+		keyCol    int
+		input     string
+		wantKeys  []string
+		wantValue map[string]string
+	}{
+		{
+			name:      "comma, key first column",
+			delimiter: ',',
+			keyCol:    0,
+			input:     "a,1,2\nb,3,4\n",
+			wantKeys:  []string{"a", "b"},
+			wantValue: map[string]string{"a": "1,2", "b": "3,4"},
+		},
+		{
+			name:      "quoted field containing delimiter",
+			delimiter: ',',
+			keyCol:    0,
+			input:     "a,\"1,2\",3\n",
+			wantKeys:  []string{"a"},
+			wantValue: map[string]string{"a": "\"1,2\",3"},
+		},
+		{
+			name:      "embedded newline",
+			delimiter: ',',
+			keyCol:    0,
+			input:     "a,\"1\n2\",3\n",
+			wantKeys:  []string{"a"},
+			wantValue: map[string]string{"a": "\"1\n2\",3"},
+		},
+		{
+			name:      "tsv with key in middle column",
+			delimiter: '\t',
+			keyCol:    1,
+			input:     "1\ta\t2\n3\tb\t4\n",
+			wantKeys:  []string{"a", "b"},
+			wantValue: map[string]string{"a": "1\t2", "b": "3\t4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m InputMap
+			var out bytes.Buffer
+			parser := NewCSVParser(context.Background(), &m, tt.delimiter, tt.keyCol)
+			if err := parser(bytes.NewReader([]byte(tt.input)), &out, nil); err != nil {
+				t.Fatalf("parser: %v", err)
+			}
+
+			got := collectKeys(&m)
+			if len(got) != len(tt.wantKeys) {
+				t.Fatalf("keys = %v, want %v", got, tt.wantKeys)
+			}
+
+			for key, want := range tt.wantValue {
+				values, ok := m.Load(key)
+				if !ok || len(values) != 1 {
+					t.Fatalf("m.Load(%q) = %v, %v", key, values, ok)
+				}
+				if got := string(values[0]); got != want {
+					t.Errorf("m.Load(%q) = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewCSVParserKeyColOutOfRange(t *testing.T) {
+	var m InputMap
+	var out bytes.Buffer
+	parser := NewCSVParser(context.Background(), &m, ',', 5)
+	if err := parser(bytes.NewReader([]byte("a,b\n")), &out, nil); err == nil {
+		t.Fatal("expected an error for an out-of-range key column")
+	}
+}
+
+func TestNewCSVParserKeyColContainsNewline(t *testing.T) {
+	var m InputMap
+	var out bytes.Buffer
+	parser := NewCSVParser(context.Background(), &m, ',', 0)
+	if err := parser(bytes.NewReader([]byte("\"a\nb\",1,2\n")), &out, nil); err == nil {
+		t.Fatal("expected an error for a key column containing a newline")
+	}
+}
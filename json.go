@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// NewJSONParser returns a Filterer that streams a JSON document from in and
+// records its fields into m, writing each discovered key to out as soon as
+// it is buffered.
+//
+// Three top-level shapes are detected from the first token:
+//
+//   - a single object: with no key path, fields are streamed directly into
+//     m, keyed by their own name, as they are discovered; with a key path,
+//     the object is treated as a record (see below).
+//   - an array of objects: each element is treated as a record (see below).
+//   - newline-delimited JSON (NDJSON), i.e. a sequence of top-level objects
+//     with no enclosing array: each object is treated as a record, the same
+//     as an array element. With no key path, the first object is streamed
+//     incrementally (see streamJSONObject) before it's known whether more
+//     documents follow, so its fields keep their bare names even when later
+//     documents turn out to be NDJSON; only the second and later documents
+//     are namespaced by position.
+//
+// keyPathExpr selects how records are keyed. When empty, a record's own
+// fields are flattened into m, namespaced by the record's position (e.g.
+// "1.foo", "2.foo") so that same-named fields across records don't collide.
+// When non-empty, it is a key path
+// such as "user.id" or "items[*].sku": the path is evaluated against the
+// whole record (the first top-level object included), and each match
+// becomes a map key under which the record's
+// value (see valuePathExpr) is stored. A wildcard segment can make a path
+// yield several matches; the same value is stored under each.
+//
+// valuePathExpr, if non-empty, is itself a key path evaluated once against
+// the record to select the value to store; it must yield exactly one
+// match. If empty, the whole record is stored as the value.
+func NewJSONParser(ctx context.Context, m *InputMap, keyPathExpr, valuePathExpr string) FilterFunc {
+	return func(in io.Reader, out, _ io.Writer) error {
+		m.Format.Kind = "json"
+
+		keyPath, err := ParseKeyPath(keyPathExpr)
+		if err != nil {
+			return err
+		}
+		valuePath, err := ParseKeyPath(valuePathExpr)
+		if err != nil {
+			return err
+		}
+
+		dec := json.NewDecoder(in)
+
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return jsonOffsetError(dec, err)
+		}
+
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			return fmt.Errorf("json: expected '{' or '[' at top level, got %v (offset %d)", tok, dec.InputOffset())
+		}
+
+		switch delim {
+		case '{':
+			if keyPath == nil {
+				if err := streamJSONObject(ctx, dec, m, out); err != nil {
+					return err
+				}
+			} else {
+				raw, err := decodeObjectBody(dec)
+				if err != nil {
+					return err
+				}
+				if err := emitJSONRecord(ctx, m, out, raw, keyPathExpr, keyPath, valuePathExpr, valuePath, 0); err != nil {
+					return err
+				}
+			}
+			return streamJSONRecords(ctx, dec, m, out, keyPathExpr, keyPath, valuePathExpr, valuePath, 1)
+		case '[':
+			return streamJSONArray(ctx, dec, m, out, keyPathExpr, keyPath, valuePathExpr, valuePath)
+		default:
+			return fmt.Errorf("json: unexpected top-level delimiter %q (offset %d)", delim, dec.InputOffset())
+		}
+	}
+}
+
+// streamJSONObject streams the key/value pairs of the object whose opening
+// '{' has already been consumed by dec, writing each key to out as soon as
+// its value has been buffered into m. Keys keep their bare names: this is
+// the only path that avoids buffering the whole object, so it's used
+// solely for the common case of a single top-level object with no key path
+// selector, and it can't yet tell whether a second top-level document will
+// follow and turn this into NDJSON (see streamJSONRecords).
+func streamJSONObject(ctx context.Context, dec *json.Decoder, m *InputMap, out io.Writer) error {
+	for dec.More() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// DO NOTHING
+		}
+
+		keyTok, err := dec.Token()
+		if err != nil {
+			return jsonOffsetError(dec, err)
+		}
+		key, ok := keyTok.(string)
+		if !ok {
+			return fmt.Errorf("json: expected object key, got %v (offset %d)", keyTok, dec.InputOffset())
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return jsonOffsetError(dec, err)
+		}
+
+		Append(&m.Map, key, []byte(raw))
+		if _, err := out.Write(append([]byte(key), LF)); err != nil {
+			return err
+		}
+	}
+
+	// consume the closing '}'
+	if _, err := dec.Token(); err != nil {
+		return jsonOffsetError(dec, err)
+	}
+	return nil
+}
+
+// decodeObjectBody decodes the remainder of an object whose opening '{' has
+// already been consumed by dec, returning it as a reconstructed
+// json.RawMessage suitable for key path evaluation. Fields are re-emitted
+// in their original order (round-tripping through a map would reorder them
+// alphabetically on marshal), so the whole record stays faithful to the
+// input when no -value projection narrows it down.
+func decodeObjectBody(dec *json.Decoder) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for first := true; dec.More(); first = false {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return nil, jsonOffsetError(dec, err)
+		}
+		key, ok := keyTok.(string)
+		if !ok {
+			return nil, fmt.Errorf("json: expected object key, got %v (offset %d)", keyTok, dec.InputOffset())
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, jsonOffsetError(dec, err)
+		}
+
+		if !first {
+			buf.WriteByte(',')
+		}
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyJSON)
+		buf.WriteByte(':')
+		buf.Write(raw)
+	}
+	if _, err := dec.Token(); err != nil { // consume '}'
+		return nil, jsonOffsetError(dec, err)
+	}
+	buf.WriteByte('}')
+	return json.RawMessage(buf.Bytes()), nil
+}
+
+// streamJSONArray streams the elements of the array whose opening '[' has
+// already been consumed by dec, treating each element as a record.
+func streamJSONArray(ctx context.Context, dec *json.Decoder, m *InputMap, out io.Writer, keyPathExpr string, keyPath []pathSegment, valuePathExpr string, valuePath []pathSegment) error {
+	for i := 0; dec.More(); i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// DO NOTHING
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return jsonOffsetError(dec, err)
+		}
+		if err := emitJSONRecord(ctx, m, out, raw, keyPathExpr, keyPath, valuePathExpr, valuePath, i); err != nil {
+			return err
+		}
+	}
+
+	// consume the closing ']'
+	if _, err := dec.Token(); err != nil {
+		return jsonOffsetError(dec, err)
+	}
+	return nil
+}
+
+// streamJSONRecords handles NDJSON: a sequence of top-level objects with no
+// enclosing array. The first object has already been handled by the
+// caller; this only does anything when more top-level tokens follow, i.e.
+// when the input is in fact NDJSON rather than a single object.
+func streamJSONRecords(ctx context.Context, dec *json.Decoder, m *InputMap, out io.Writer, keyPathExpr string, keyPath []pathSegment, valuePathExpr string, valuePath []pathSegment, index int) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// DO NOTHING
+		}
+
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return jsonOffsetError(dec, err)
+		}
+
+		delim, ok := tok.(json.Delim)
+		if !ok || delim != '{' {
+			return fmt.Errorf("json: expected NDJSON object, got %v (offset %d)", tok, dec.InputOffset())
+		}
+
+		raw, err := decodeObjectBody(dec)
+		if err != nil {
+			return err
+		}
+		if err := emitJSONRecord(ctx, m, out, raw, keyPathExpr, keyPath, valuePathExpr, valuePath, index); err != nil {
+			return err
+		}
+		index++
+	}
+}
+
+// emitJSONRecord stores a single array element or NDJSON record (already
+// fully decoded as raw) into m. With no keyPath, the record's own fields
+// are flattened into m, namespaced by index. With a keyPath, it is
+// evaluated against the record to pick the map key(s); the value stored
+// under each is either the whole record or, if valuePath is set, the single
+// value it selects.
+func emitJSONRecord(ctx context.Context, m *InputMap, out io.Writer, raw json.RawMessage, keyPathExpr string, keyPath []pathSegment, valuePathExpr string, valuePath []pathSegment, index int) error {
+	if keyPath == nil {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return fmt.Errorf("json: record %d: %w", index, err)
+		}
+		prefix := fmt.Sprintf("%d", index)
+		for key, v := range fields {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+				// DO NOTHING
+			}
+			mapKey := prefix + "." + key
+			Append(&m.Map, mapKey, []byte(v))
+			if _, err := out.Write(append([]byte(mapKey), LF)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	keyMatches, err := EvalKeyPath(keyPathExpr, raw, keyPath)
+	if err != nil {
+		return fmt.Errorf("json: record %d: %w", index, err)
+	}
+
+	value := raw
+	if valuePath != nil {
+		valueMatches, err := EvalKeyPath(valuePathExpr, raw, valuePath)
+		if err != nil {
+			return fmt.Errorf("json: record %d: %w", index, err)
+		}
+		if len(valueMatches) != 1 {
+			return fmt.Errorf("json: record %d: -value %q matched %d values, want exactly 1", index, valuePathExpr, len(valueMatches))
+		}
+		value = valueMatches[0]
+	}
+
+	for _, km := range keyMatches {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// DO NOTHING
+		}
+		mapKey := keyString(km)
+		Append(&m.Map, mapKey, []byte(value))
+		if _, err := out.Write(append([]byte(mapKey), LF)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func jsonOffsetError(dec *json.Decoder, err error) error {
+	return fmt.Errorf("json: %w (offset %d)", err, dec.InputOffset())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+)
+
+// collectKeys returns the map's keys in sorted order, for deterministic
+// comparison against a want slice.
+func collectKeys(m *InputMap) []string {
+	var keys []string
+	m.Range(func(k, _ any) bool {
+		keys = append(keys, k.(string))
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+func TestNewJSONParserShapes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "single object",
+			input: `{"foo":"1"}`,
+			want:  []string{"foo"},
+		},
+		{
+			name:  "array of objects",
+			input: `[{"foo":"1"},{"foo":"2"}]`,
+			want:  []string{"0.foo", "1.foo"},
+		},
+		{
+			name:  "ndjson",
+			input: "{\"foo\":\"1\"}\n{\"foo\":\"2\"}\n",
+			want:  []string{"foo", "1.foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m InputMap
+			var out bytes.Buffer
+			parser := NewJSONParser(context.Background(), &m, "", "")
+			if err := parser(bytes.NewReader([]byte(tt.input)), &out, nil); err != nil {
+				t.Fatalf("parser: %v", err)
+			}
+
+			got := collectKeys(&m)
+			sort.Strings(tt.want)
+			if len(got) != len(tt.want) {
+				t.Fatalf("keys = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("keys = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewJSONParserKeyPath(t *testing.T) {
+	var m InputMap
+	var out bytes.Buffer
+	parser := NewJSONParser(context.Background(), &m, "id", "")
+	input := `[{"id":"a","v":1},{"id":"b","v":2}]`
+	if err := parser(bytes.NewReader([]byte(input)), &out, nil); err != nil {
+		t.Fatalf("parser: %v", err)
+	}
+
+	got := collectKeys(&m)
+	want := []string{"a", "b"}
+	if len(got) != len(want) {
+		t.Fatalf("keys = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("keys = %v, want %v", got, want)
+		}
+	}
+}
+
+// TestNewJSONParserKeyPathPreservesFieldOrder ensures decodeObjectBody
+// doesn't round-trip a record through a map, which would reorder its
+// top-level fields alphabetically.
+func TestNewJSONParserKeyPathPreservesFieldOrder(t *testing.T) {
+	var m InputMap
+	var out bytes.Buffer
+	parser := NewJSONParser(context.Background(), &m, "a", "")
+	input := "{\"b\":1,\"a\":2}\n{\"b\":3,\"a\":4}\n"
+	if err := parser(bytes.NewReader([]byte(input)), &out, nil); err != nil {
+		t.Fatalf("parser: %v", err)
+	}
+
+	values, ok := m.Load("2")
+	if !ok || len(values) != 1 {
+		t.Fatalf("m.Load(%q) = %v, %v", "2", values, ok)
+	}
+	if got, want := string(values[0]), `{"b":1,"a":2}`; got != want {
+		t.Errorf("stored record = %s, want %s (fields reordered)", got, want)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -35,6 +34,14 @@ var invalidArgument = errors.New("invalidArgument")
 func run() error {
 	delim := flag.String("d", " ", "delimiter(default is a space)")
 	isJson := flag.Bool("json", false, "parse JSON object")
+	keyPath := flag.String("key", "", "key path selecting the map key within each JSON record, e.g. user.id or items[*].sku (default: namespace record fields by position)")
+	valuePath := flag.String("value", "", "key path selecting the value stored for each JSON record (default: the whole record)")
+	isCSV := flag.Bool("csv", false, "parse CSV input")
+	isTSV := flag.Bool("tsv", false, "parse TSV input")
+	keyCol := flag.Int("key-col", 0, "column index used as the map key when parsing CSV/TSV input")
+	isYAML := flag.Bool("yaml", false, "parse YAML input")
+	yamlValueAs := flag.String("yaml-value-as", "yaml", "format used to store YAML values: yaml or json")
+	output := flag.String("output", "raw", "output format: raw, json, csv or tsv")
 
 	commandArgs, err := extractCommandArgs()
 	if err != nil {
@@ -49,9 +56,16 @@ func run() error {
 		parser Filterer
 	)
 
-	if *isJson {
-		parser = NewJSONParser(ctx, &m)
-	} else {
+	switch {
+	case *isJson:
+		parser = NewJSONParser(ctx, &m, *keyPath, *valuePath)
+	case *isCSV:
+		parser = NewCSVParser(ctx, &m, ',', *keyCol)
+	case *isTSV:
+		parser = NewCSVParser(ctx, &m, '\t', *keyCol)
+	case *isYAML:
+		parser = NewYAMLParser(ctx, &m, *yamlValueAs)
+	default:
 		parser = NewParserByDelimiter(ctx, &m, []byte(*delim))
 	}
 
@@ -61,7 +75,7 @@ func run() error {
 		os.Stderr,
 		parser,
 		NewCommandExecutor(ctx, commandArgs),
-		NewResultMapper(ctx, &m),
+		NewResultMapper(ctx, &m, *output),
 	)
 
 	for _, r := range runners {
@@ -116,7 +130,25 @@ func Append[K comparable, V any](m *Map[K, []V], key K, value V) {
 	m.Store(key, append(values, value))
 }
 
-type InputMap = Map[string, [][]byte]
+// Format records how the input was shaped, so NewResultMapper can
+// reassemble output that mirrors it instead of always falling back to raw
+// LF-separated values.
+type Format struct {
+	// Kind is "json", "csv", "yaml" or "" (raw/delimiter input, the
+	// default).
+	Kind string
+	// Columns names the value columns (key column excluded) of CSV/TSV
+	// input, used to synthesize a header row on CSV/TSV output.
+	Columns []string
+	// Delimiter is the field delimiter CSV/TSV input was parsed with,
+	// used to reject an -output=csv/tsv that doesn't match it.
+	Delimiter rune
+}
+
+type InputMap struct {
+	Map[string, [][]byte]
+	Format Format
+}
 
 type FilterFunc func(io.Reader, io.Writer, io.Writer) error
 
@@ -143,7 +175,7 @@ func NewParserByDelimiter(ctx context.Context, m *InputMap, delimiter []byte) Fi
 			}
 
 			k, v := tsv[0], tsv[1]
-			Append(m, string(k), v)
+			Append(&m.Map, string(k), v)
 			_, err := out.Write(append(k, LF))
 			if err != nil {
 				return err
@@ -153,32 +185,6 @@ func NewParserByDelimiter(ctx context.Context, m *InputMap, delimiter []byte) Fi
 	}
 }
 
-func NewJSONParser(ctx context.Context, m *InputMap) FilterFunc {
-	return func(in io.Reader, out, _ io.Writer) error {
-		var mb map[string]json.RawMessage
-		err := json.NewDecoder(in).Decode(&mb)
-		if err != nil {
-			return err
-		}
-
-		for k, v := range mb {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-				// DO NOTHING
-			}
-
-			Append[string, []byte](m, k, v)
-			_, err := out.Write([]byte(k))
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	}
-}
-
 func NewCommandExecutor(ctx context.Context, commandArgs []string) FilterFunc {
 	return func(in io.Reader, out, errout io.Writer) error {
 		command := commandArgs[0]
@@ -194,48 +200,6 @@ func NewCommandExecutor(ctx context.Context, commandArgs []string) FilterFunc {
 	}
 }
 
-func NewResultMapper(ctx context.Context, m *InputMap) FilterFunc {
-	return func(in io.Reader, out, _ io.Writer) error {
-		scanner := bufio.NewScanner(in)
-		var keys []string
-		for scanner.Scan() {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-				// DO NOTHING
-			}
-
-			keys = append(keys, scanner.Text())
-		}
-		err := scanner.Err()
-		if err != nil {
-			log.Println("error:", err)
-			return err
-		}
-		set := make(map[string]struct{})
-		for _, key := range keys {
-			_, duplicated := set[key]
-			if duplicated {
-				continue
-			}
-			set[key] = struct{}{}
-
-			values, ok := m.Load(key)
-			if !ok {
-				return fmt.Errorf("not found by key: %s, map: %#v", key, m)
-			}
-			for _, value := range values {
-				_, err := out.Write(append(value, LF))
-				if err != nil {
-					return err
-				}
-			}
-		}
-		return nil
-	}
-}
-
 type Runner interface {
 	Run() error
 }
@@ -246,31 +210,37 @@ func (r RunnerFunc) Run() error {
 	return r()
 }
 
+// NewFilterRunner runs f, closing whichever ends of a pipe it owns with f's
+// own error once it returns. Closing the write end with the error unblocks
+// a downstream stage that's still reading; closing the read end unblocks
+// an upstream stage that's still writing. Without both, a stage that exits
+// early (e.g. on its own error) can leave its neighbours blocked on a pipe
+// that will never be closed.
 func NewFilterRunner(f Filterer, in io.Reader, out, errout io.Writer) RunnerFunc {
-	return func() error {
-		if closer, ok := out.(io.Closer); ok {
-			defer closer.Close()
+	return func() (err error) {
+		if pw, ok := out.(*io.PipeWriter); ok {
+			defer func() { pw.CloseWithError(err) }()
+		}
+		if pr, ok := in.(*io.PipeReader); ok {
+			defer func() { pr.CloseWithError(err) }()
 		}
 
-		return f.Filter(in, out, errout)
+		err = f.Filter(in, out, errout)
+		return err
 	}
 }
 
 func Chain(in io.Reader, out, errout io.Writer, filterers ...Filterer) []Runner {
-	var (
-		runners  []Runner
-		nextIn   io.Reader
-		finalOut = out
-	)
+	var runners []Runner
 
 	for i, f := range filterers {
 		if i == len(filterers)-1 {
-			runners = append(runners, NewFilterRunner(f, in, finalOut, errout))
+			runners = append(runners, NewFilterRunner(f, in, out, errout))
 			break
 		}
-		nextIn, out = io.Pipe()
-		runners = append(runners, NewFilterRunner(f, in, out, errout))
-		in = nextIn
+		pr, pw := io.Pipe()
+		runners = append(runners, NewFilterRunner(f, in, pw, errout))
+		in = pr
 	}
 
 	return runners
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestChainUnblocksAllStagesOnError ensures that when one stage in a Chain
+// fails, every stage's Run returns promptly instead of deadlocking on a
+// pipe that never gets closed.
+func TestChainUnblocksAllStagesOnError(t *testing.T) {
+	wantErr := errors.New("boom")
+
+	failing := FilterFunc(func(_ io.Reader, _, _ io.Writer) error {
+		return wantErr
+	})
+	passthrough := FilterFunc(func(in io.Reader, out, _ io.Writer) error {
+		_, err := io.Copy(out, in)
+		return err
+	})
+
+	var out, errOut bytes.Buffer
+	runners := Chain(strings.NewReader("ignored"), &out, &errOut, failing, passthrough, passthrough)
+
+	results := make(chan error, len(runners))
+	for _, r := range runners {
+		r := r
+		go func() { results <- r.Run() }()
+	}
+
+	var gotFailingErr bool
+	deadline := time.After(2 * time.Second)
+	for range runners {
+		select {
+		case err := <-results:
+			if errors.Is(err, wantErr) {
+				gotFailingErr = true
+			}
+		case <-deadline:
+			t.Fatal("not all stages returned before the deadline; a pipe was likely left open")
+		}
+	}
+
+	if !gotFailingErr {
+		t.Error("expected the failing stage's error to be observed")
+	}
+}
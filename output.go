@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+)
+
+// NewResultMapper reads the keys the command chose to keep, one per line,
+// and writes their stored values back out. output selects how: "raw" (the
+// default) writes each stored value LF-separated, same as the delimiter
+// and legacy JSON parsers always produced; "json" reassembles the kept
+// keys into a JSON object; "csv"/"tsv" reassemble them into CSV/TSV rows,
+// using m.Format to recover the shape of the original input.
+func NewResultMapper(ctx context.Context, m *InputMap, output string) FilterFunc {
+	return func(in io.Reader, out, _ io.Writer) error {
+		scanner := bufio.NewScanner(in)
+		var keys []string
+		for scanner.Scan() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+				// DO NOTHING
+			}
+
+			keys = append(keys, scanner.Text())
+		}
+		err := scanner.Err()
+		if err != nil {
+			log.Println("error:", err)
+			return err
+		}
+
+		set := make(map[string]struct{})
+		var uniqueKeys []string
+		for _, key := range keys {
+			if _, duplicated := set[key]; duplicated {
+				continue
+			}
+			set[key] = struct{}{}
+			uniqueKeys = append(uniqueKeys, key)
+		}
+
+		switch output {
+		case "json":
+			if m.Format.Kind != "json" {
+				return fmt.Errorf("output: -output=json requires -json input (input was %s)", formatKindName(m.Format.Kind))
+			}
+			return writeJSONResult(m, out, uniqueKeys)
+		case "csv":
+			if m.Format.Kind != "csv" {
+				return fmt.Errorf("output: -output=csv requires -csv or -tsv input (input was %s)", formatKindName(m.Format.Kind))
+			}
+			if m.Format.Delimiter != ',' {
+				return fmt.Errorf("output: -output=csv requires -csv input (input was -tsv)")
+			}
+			return writeCSVResult(m, out, uniqueKeys, ',')
+		case "tsv":
+			if m.Format.Kind != "csv" {
+				return fmt.Errorf("output: -output=tsv requires -csv or -tsv input (input was %s)", formatKindName(m.Format.Kind))
+			}
+			if m.Format.Delimiter != '\t' {
+				return fmt.Errorf("output: -output=tsv requires -tsv input (input was -csv)")
+			}
+			return writeCSVResult(m, out, uniqueKeys, '\t')
+		default:
+			return writeRawResult(m, out, uniqueKeys)
+		}
+	}
+}
+
+// formatKindName renders m.Format.Kind for error messages, naming the
+// default raw/delimiter input explicitly instead of printing "".
+func formatKindName(kind string) string {
+	if kind == "" {
+		return "raw/delimiter"
+	}
+	return kind
+}
+
+func writeRawResult(m *InputMap, out io.Writer, keys []string) error {
+	for _, key := range keys {
+		values, ok := m.Load(key)
+		if !ok {
+			return fmt.Errorf("not found by key: %s, map: %#v", key, m)
+		}
+		for _, value := range values {
+			if _, err := out.Write(append(value, LF)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeJSONResult(m *InputMap, out io.Writer, keys []string) error {
+	obj := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		values, ok := m.Load(key)
+		if !ok {
+			return fmt.Errorf("not found by key: %s, map: %#v", key, m)
+		}
+		if len(values) == 1 {
+			obj[key] = json.RawMessage(values[0])
+			continue
+		}
+		arr := make([]json.RawMessage, len(values))
+		for i, v := range values {
+			arr[i] = json.RawMessage(v)
+		}
+		b, err := json.Marshal(arr)
+		if err != nil {
+			return err
+		}
+		obj[key] = b
+	}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(b, LF))
+	return err
+}
+
+func writeCSVResult(m *InputMap, out io.Writer, keys []string, delimiter rune) error {
+	w := csv.NewWriter(out)
+	w.Comma = delimiter
+
+	if err := w.Write(append([]string{"key"}, m.Format.Columns...)); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		values, ok := m.Load(key)
+		if !ok {
+			return fmt.Errorf("not found by key: %s, map: %#v", key, m)
+		}
+		for _, value := range values {
+			r := csv.NewReader(bytes.NewReader(value))
+			r.Comma = delimiter
+			fields, err := r.Read()
+			if err != nil {
+				return fmt.Errorf("csv: %w", err)
+			}
+			if err := w.Write(append([]string{key}, fields...)); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
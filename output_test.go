@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNewResultMapperFormatMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		output  string
+		wantErr bool
+	}{
+		{name: "json output, json input", format: Format{Kind: "json"}, output: "json", wantErr: false},
+		{name: "json output, raw input", format: Format{Kind: ""}, output: "json", wantErr: true},
+		{name: "json output, csv input", format: Format{Kind: "csv", Delimiter: ','}, output: "json", wantErr: true},
+		{name: "json output, yaml input", format: Format{Kind: "yaml"}, output: "json", wantErr: true},
+		{name: "csv output, csv input", format: Format{Kind: "csv", Delimiter: ','}, output: "csv", wantErr: false},
+		{name: "csv output, raw input", format: Format{Kind: ""}, output: "csv", wantErr: true},
+		{name: "csv output, tsv input", format: Format{Kind: "csv", Delimiter: '\t'}, output: "csv", wantErr: true},
+		{name: "tsv output, raw input", format: Format{Kind: ""}, output: "tsv", wantErr: true},
+		{name: "tsv output, tsv input", format: Format{Kind: "csv", Delimiter: '\t'}, output: "tsv", wantErr: false},
+		{name: "tsv output, csv input", format: Format{Kind: "csv", Delimiter: ','}, output: "tsv", wantErr: true},
+		{name: "raw output, raw input", format: Format{Kind: ""}, output: "raw", wantErr: false},
+		{name: "raw output, json input", format: Format{Kind: "json"}, output: "raw", wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m InputMap
+			m.Format = tt.format
+			value := []byte(`"v"`)
+			if tt.format.Kind == "csv" {
+				value = []byte("v")
+				m.Format.Columns = []string{"1"}
+			}
+			Append(&m.Map, "k", value)
+
+			var out bytes.Buffer
+			mapper := NewResultMapper(context.Background(), &m, tt.output)
+			err := mapper(strings.NewReader("k\n"), &out, nil)
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected an error for %s output against %s input", tt.output, formatKindName(tt.format.Kind))
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is one step of a key path such as "items[*].sku": a field
+// lookup, an array index, or a wildcard over an array.
+type pathSegment struct {
+	kind  pathSegmentKind
+	field string
+	index int
+}
+
+type pathSegmentKind int
+
+const (
+	pathField pathSegmentKind = iota
+	pathIndex
+	pathWildcard
+)
+
+func (s pathSegment) String() string {
+	switch s.kind {
+	case pathField:
+		return s.field
+	case pathIndex:
+		return fmt.Sprintf("[%d]", s.index)
+	case pathWildcard:
+		return "[*]"
+	default:
+		return "?"
+	}
+}
+
+// ParseKeyPath parses a dotted / bracketed selector such as "user.id" or
+// "items[*].sku" into a sequence of path segments. An empty expr yields a
+// nil, nil result.
+func ParseKeyPath(expr string) ([]pathSegment, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	var segs []pathSegment
+	i := 0
+	for i < len(expr) {
+		switch expr[i] {
+		case '.':
+			i++
+		case '[':
+			j := strings.IndexByte(expr[i:], ']')
+			if j < 0 {
+				return nil, fmt.Errorf("key path %q: unterminated '[' at offset %d", expr, i)
+			}
+			inner := expr[i+1 : i+j]
+			if inner == "*" {
+				segs = append(segs, pathSegment{kind: pathWildcard})
+			} else {
+				idx, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("key path %q: invalid index %q at offset %d", expr, inner, i)
+				}
+				segs = append(segs, pathSegment{kind: pathIndex, index: idx})
+			}
+			i += j + 1
+		default:
+			end := i
+			for end < len(expr) && expr[end] != '.' && expr[end] != '[' {
+				end++
+			}
+			segs = append(segs, pathSegment{kind: pathField, field: expr[i:end]})
+			i = end
+		}
+	}
+	return segs, nil
+}
+
+// EvalKeyPath evaluates segs against the decoded JSON tree in raw, returning
+// every value the path matches (more than one only when a wildcard segment
+// is involved). It reports the path expression and the failing segment on
+// error so selectors can be debugged interactively.
+func EvalKeyPath(expr string, raw json.RawMessage, segs []pathSegment) ([]json.RawMessage, error) {
+	current := []json.RawMessage{raw}
+
+	for i, seg := range segs {
+		var next []json.RawMessage
+		for _, cur := range current {
+			switch seg.kind {
+			case pathField:
+				var obj map[string]json.RawMessage
+				if err := json.Unmarshal(cur, &obj); err != nil {
+					return nil, fmt.Errorf("key path %q: segment %q (%d): not an object: %w", expr, seg, i, err)
+				}
+				v, ok := obj[seg.field]
+				if !ok {
+					return nil, fmt.Errorf("key path %q: segment %q (%d): field not found", expr, seg, i)
+				}
+				next = append(next, v)
+			case pathIndex:
+				var arr []json.RawMessage
+				if err := json.Unmarshal(cur, &arr); err != nil {
+					return nil, fmt.Errorf("key path %q: segment %q (%d): not an array: %w", expr, seg, i, err)
+				}
+				if seg.index < 0 || seg.index >= len(arr) {
+					return nil, fmt.Errorf("key path %q: segment %q (%d): index out of range (len %d)", expr, seg, i, len(arr))
+				}
+				next = append(next, arr[seg.index])
+			case pathWildcard:
+				var arr []json.RawMessage
+				if err := json.Unmarshal(cur, &arr); err != nil {
+					return nil, fmt.Errorf("key path %q: segment %q (%d): not an array: %w", expr, seg, i, err)
+				}
+				next = append(next, arr...)
+			default:
+				return nil, fmt.Errorf("key path %q: segment %q (%d): unknown segment kind", expr, seg, i)
+			}
+		}
+		current = next
+	}
+
+	return current, nil
+}
+
+// keyString renders a matched JSON value as the map key / text emitted to
+// the child command: strings lose their quotes, everything else is used as
+// its literal JSON text.
+func keyString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
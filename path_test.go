@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyPath(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    []pathSegment
+		wantErr bool
+	}{
+		{expr: "", want: nil},
+		{expr: "id", want: []pathSegment{{kind: pathField, field: "id"}}},
+		{
+			expr: "user.id",
+			want: []pathSegment{
+				{kind: pathField, field: "user"},
+				{kind: pathField, field: "id"},
+			},
+		},
+		{
+			expr: "items[*].sku",
+			want: []pathSegment{
+				{kind: pathField, field: "items"},
+				{kind: pathWildcard},
+				{kind: pathField, field: "sku"},
+			},
+		},
+		{
+			expr: "items[0]",
+			want: []pathSegment{
+				{kind: pathField, field: "items"},
+				{kind: pathIndex, index: 0},
+			},
+		},
+		{expr: "items[", wantErr: true},
+		{expr: "items[x]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseKeyPath(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseKeyPath(%q) = %v, want error", tt.expr, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKeyPath(%q): %v", tt.expr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseKeyPath(%q) = %#v, want %#v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalKeyPath(t *testing.T) {
+	raw := json.RawMessage(`{"user":{"id":"u1"},"items":[{"sku":"a"},{"sku":"b"}]}`)
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "field", expr: "user.id", want: []string{`"u1"`}},
+		{name: "wildcard", expr: "items[*].sku", want: []string{`"a"`, `"b"`}},
+		{name: "index", expr: "items[1].sku", want: []string{`"b"`}},
+		{name: "missing field", expr: "user.name", wantErr: true},
+		{name: "out of range", expr: "items[5]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segs, err := ParseKeyPath(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseKeyPath(%q): %v", tt.expr, err)
+			}
+			got, err := EvalKeyPath(tt.expr, raw, segs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("EvalKeyPath(%q) = %v, want error", tt.expr, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EvalKeyPath(%q): %v", tt.expr, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("EvalKeyPath(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+			for i := range got {
+				if string(got[i]) != tt.want[i] {
+					t.Fatalf("EvalKeyPath(%q)[%d] = %s, want %s", tt.expr, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestKeyString(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: `"foo"`, want: "foo"},
+		{raw: `42`, want: "42"},
+		{raw: `true`, want: "true"},
+	}
+
+	for _, tt := range tests {
+		got := keyString(json.RawMessage(tt.raw))
+		if got != tt.want {
+			t.Errorf("keyString(%s) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
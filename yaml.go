@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NewYAMLParser returns a Filterer that parses a YAML document from in and
+// records its fields into m, writing each discovered key to out, mirroring
+// NewJSONParser's three-shape handling:
+//
+//   - a single top-level mapping: its fields become map keys directly.
+//   - a single top-level sequence of mappings: each element is a record,
+//     namespaced by its position (e.g. "0.foo", "1.foo"), analogous to
+//     NewJSONParser's array handling.
+//   - a multi-document YAML stream (documents separated by "---"): each
+//     document is a record, namespaced by its position in the stream.
+//
+// Each value is stored re-serialized as YAML, unless valueAs is "json", in
+// which case it is stored as JSON.
+func NewYAMLParser(ctx context.Context, m *InputMap, valueAs string) FilterFunc {
+	return func(in io.Reader, out, _ io.Writer) error {
+		m.Format.Kind = "yaml"
+
+		dec := yaml.NewDecoder(in)
+
+		var docs []*yaml.Node
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+				// DO NOTHING
+			}
+
+			var doc yaml.Node
+			err := dec.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("yaml: %w", err)
+			}
+			docs = append(docs, &doc)
+		}
+
+		if len(docs) == 0 {
+			return nil
+		}
+
+		if len(docs) == 1 {
+			root := yamlRoot(docs[0])
+			switch root.Kind {
+			case yaml.MappingNode:
+				return emitYAMLMapping(ctx, m, out, root, "", valueAs)
+			case yaml.SequenceNode:
+				for i, el := range root.Content {
+					if err := emitYAMLRecord(ctx, m, out, el, i, valueAs); err != nil {
+						return err
+					}
+				}
+				return nil
+			default:
+				return fmt.Errorf("yaml: unsupported top-level node kind %d", root.Kind)
+			}
+		}
+
+		for i, doc := range docs {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+				// DO NOTHING
+			}
+			if err := emitYAMLRecord(ctx, m, out, yamlRoot(doc), i, valueAs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// yamlRoot unwraps the document node yaml.Decoder produces down to its
+// actual content node.
+func yamlRoot(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0]
+	}
+	return node
+}
+
+// emitYAMLRecord stores a single sequence element or stream document,
+// namespacing its fields by index the way NewJSONParser does for array and
+// NDJSON records.
+func emitYAMLRecord(ctx context.Context, m *InputMap, out io.Writer, node *yaml.Node, index int, valueAs string) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("yaml: record %d: expected a mapping, got node kind %d", index, node.Kind)
+	}
+	return emitYAMLMapping(ctx, m, out, node, fmt.Sprintf("%d", index), valueAs)
+}
+
+// emitYAMLMapping stores each key/value pair of a mapping node into m,
+// prefixing keys (if prefix is non-empty) to namespace them.
+func emitYAMLMapping(ctx context.Context, m *InputMap, out io.Writer, node *yaml.Node, prefix string, valueAs string) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// DO NOTHING
+		}
+
+		keyNode, valNode := node.Content[i], node.Content[i+1]
+		key := keyNode.Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		encoded, err := encodeYAMLValue(valNode, valueAs)
+		if err != nil {
+			return fmt.Errorf("yaml: key %q: %w", key, err)
+		}
+
+		Append(&m.Map, key, encoded)
+		if _, err := out.Write(append([]byte(key), LF)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeYAMLValue serializes node as YAML, or as JSON when valueAs is
+// "json".
+func encodeYAMLValue(node *yaml.Node, valueAs string) ([]byte, error) {
+	if valueAs == "json" {
+		var v any
+		if err := node.Decode(&v); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}
+
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(out, "\n"), nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestNewYAMLParserShapes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "single mapping",
+			input: "foo: 1\n",
+			want:  []string{"foo"},
+		},
+		{
+			name:  "sequence of mappings",
+			input: "- foo: 1\n- foo: 2\n",
+			want:  []string{"0.foo", "1.foo"},
+		},
+		{
+			name:  "multi-document stream",
+			input: "foo: 1\n---\nfoo: 2\n",
+			want:  []string{"0.foo", "1.foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m InputMap
+			var out bytes.Buffer
+			parser := NewYAMLParser(context.Background(), &m, "yaml")
+			if err := parser(bytes.NewReader([]byte(tt.input)), &out, nil); err != nil {
+				t.Fatalf("parser: %v", err)
+			}
+
+			got := collectKeys(&m)
+			if len(got) != len(tt.want) {
+				t.Fatalf("keys = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("keys = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewYAMLParserSetsFormatKind(t *testing.T) {
+	var m InputMap
+	var out bytes.Buffer
+	parser := NewYAMLParser(context.Background(), &m, "yaml")
+	if err := parser(bytes.NewReader([]byte("foo: 1\n")), &out, nil); err != nil {
+		t.Fatalf("parser: %v", err)
+	}
+
+	if m.Format.Kind != "yaml" {
+		t.Errorf("m.Format.Kind = %q, want %q", m.Format.Kind, "yaml")
+	}
+}
+
+func TestNewYAMLParserValueAsJSON(t *testing.T) {
+	var m InputMap
+	var out bytes.Buffer
+	parser := NewYAMLParser(context.Background(), &m, "json")
+	if err := parser(bytes.NewReader([]byte("foo:\n  bar: 1\n")), &out, nil); err != nil {
+		t.Fatalf("parser: %v", err)
+	}
+
+	values, ok := m.Load("foo")
+	if !ok || len(values) != 1 {
+		t.Fatalf("m.Load(%q) = %v, %v", "foo", values, ok)
+	}
+	if got, want := string(values[0]), `{"bar":1}`; got != want {
+		t.Errorf("stored value = %s, want %s", got, want)
+	}
+}